@@ -0,0 +1,122 @@
+// Package cloudevents maps between amqp.Delivery/amqp.Publishing and
+// CloudEvents v1.0, in both binary and structured content mode.
+package cloudevents
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"github.com/streadway/amqp"
+)
+
+const (
+	// HeaderPrefix prefixes every CloudEvents context attribute carried as
+	// an AMQP header in binary mode, e.g. "cloudEvents:id".
+	HeaderPrefix = "cloudEvents:"
+
+	// StructuredContentType is the Publishing.ContentType used in
+	// structured mode, where the whole event is the message body.
+	StructuredContentType = "application/cloudevents+json"
+
+	headerTraceParent = "traceparent"
+	headerTraceState  = "tracestate"
+)
+
+// ErrNotCloudEvent is returned when a Delivery carries neither a
+// structured-mode CloudEvents body nor the minimum binary-mode headers
+// (id, source, type, specversion).
+var ErrNotCloudEvent = errors.New("cloudevents: delivery is not a CloudEvent")
+
+// ToBinaryPublishing renders event in binary content mode: context
+// attributes go into AMQP headers under HeaderPrefix, the payload is the
+// AMQP body, and ContentType is taken from the event's datacontenttype.
+func ToBinaryPublishing(event ce.Event) (amqp.Publishing, error) {
+	msg := amqp.Publishing{
+		ContentType: event.DataContentType(),
+		Body:        event.Data(),
+		Headers:     amqp.Table{},
+	}
+	msg.Headers[HeaderPrefix+"id"] = event.ID()
+	msg.Headers[HeaderPrefix+"source"] = event.Source()
+	msg.Headers[HeaderPrefix+"type"] = event.Type()
+	msg.Headers[HeaderPrefix+"specversion"] = event.SpecVersion()
+	if st := event.Subject(); st != "" {
+		msg.Headers[HeaderPrefix+"subject"] = st
+	}
+	if !event.Time().IsZero() {
+		msg.Headers[HeaderPrefix+"time"] = event.Time().Format(time.RFC3339)
+	}
+	for k, v := range event.Extensions() {
+		msg.Headers[HeaderPrefix+k] = v
+	}
+	return msg, nil
+}
+
+// ToStructuredPublishing renders event in structured content mode: the
+// whole event, including its payload, is JSON-serialized as the AMQP body.
+func ToStructuredPublishing(event ce.Event) (amqp.Publishing, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+	return amqp.Publishing{
+		ContentType: StructuredContentType,
+		Body:        body,
+	}, nil
+}
+
+// FromDelivery reconstructs a CloudEvents Event from d, detecting
+// structured mode by ContentType and falling back to binary mode headers
+// otherwise.
+func FromDelivery(d amqp.Delivery) (ce.Event, error) {
+	if d.ContentType == StructuredContentType {
+		var event ce.Event
+		if err := json.Unmarshal(d.Body, &event); err != nil {
+			return ce.Event{}, err
+		}
+		return event, nil
+	}
+	return fromBinaryDelivery(d)
+}
+
+func fromBinaryDelivery(d amqp.Delivery) (ce.Event, error) {
+	id, okID := d.Headers[HeaderPrefix+"id"].(string)
+	source, okSource := d.Headers[HeaderPrefix+"source"].(string)
+	typ, okType := d.Headers[HeaderPrefix+"type"].(string)
+	specVersion, okSpec := d.Headers[HeaderPrefix+"specversion"].(string)
+	if !okID || !okSource || !okType || !okSpec {
+		return ce.Event{}, ErrNotCloudEvent
+	}
+
+	event := ce.NewEvent(specVersion)
+	event.SetID(id)
+	event.SetSource(source)
+	event.SetType(typ)
+	if subject, ok := d.Headers[HeaderPrefix+"subject"].(string); ok {
+		event.SetSubject(subject)
+	}
+	if ts, ok := d.Headers[HeaderPrefix+"time"].(string); ok {
+		parsed, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return ce.Event{}, err
+		}
+		event.SetTime(parsed)
+	}
+	if err := event.SetData(d.ContentType, d.Body); err != nil {
+		return ce.Event{}, err
+	}
+	for k, v := range d.Headers {
+		if len(k) <= len(HeaderPrefix) || k[:len(HeaderPrefix)] != HeaderPrefix {
+			continue
+		}
+		name := k[len(HeaderPrefix):]
+		switch name {
+		case "id", "source", "type", "specversion", "subject", "time":
+			continue
+		}
+		event.SetExtension(name, v)
+	}
+	return event, nil
+}
@@ -0,0 +1,96 @@
+package cloudevents
+
+import (
+	"testing"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"github.com/streadway/amqp"
+)
+
+func newTestEvent(t *testing.T) ce.Event {
+	t.Helper()
+	event := ce.NewEvent()
+	event.SetID("event-1")
+	event.SetSource("test/source")
+	event.SetType("test.type")
+	event.SetSubject("test-subject")
+	event.SetTime(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	event.SetExtension("traceparent", "00-trace-01")
+	if err := event.SetData("application/json", []byte(`{"k":"v"}`)); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	return event
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	event := newTestEvent(t)
+
+	msg, err := ToBinaryPublishing(event)
+	if err != nil {
+		t.Fatalf("ToBinaryPublishing: %v", err)
+	}
+	if msg.ContentType != event.DataContentType() {
+		t.Fatalf("ContentType = %q, want %q", msg.ContentType, event.DataContentType())
+	}
+
+	got, err := FromDelivery(amqp.Delivery{ContentType: msg.ContentType, Body: msg.Body, Headers: msg.Headers})
+	if err != nil {
+		t.Fatalf("FromDelivery: %v", err)
+	}
+
+	if got.ID() != event.ID() {
+		t.Fatalf("ID = %q, want %q", got.ID(), event.ID())
+	}
+	if got.Source() != event.Source() {
+		t.Fatalf("Source = %q, want %q", got.Source(), event.Source())
+	}
+	if got.Type() != event.Type() {
+		t.Fatalf("Type = %q, want %q", got.Type(), event.Type())
+	}
+	if got.Subject() != event.Subject() {
+		t.Fatalf("Subject = %q, want %q", got.Subject(), event.Subject())
+	}
+	if !got.Time().Equal(event.Time()) {
+		t.Fatalf("Time = %v, want %v", got.Time(), event.Time())
+	}
+	if string(got.Data()) != string(event.Data()) {
+		t.Fatalf("Data = %q, want %q", got.Data(), event.Data())
+	}
+	if tp, ok := got.Extensions()["traceparent"].(string); !ok || tp != "00-trace-01" {
+		t.Fatalf("traceparent extension = %v, want %q", got.Extensions()["traceparent"], "00-trace-01")
+	}
+}
+
+func TestStructuredRoundTrip(t *testing.T) {
+	event := newTestEvent(t)
+
+	msg, err := ToStructuredPublishing(event)
+	if err != nil {
+		t.Fatalf("ToStructuredPublishing: %v", err)
+	}
+	if msg.ContentType != StructuredContentType {
+		t.Fatalf("ContentType = %q, want %q", msg.ContentType, StructuredContentType)
+	}
+
+	got, err := FromDelivery(amqp.Delivery{ContentType: msg.ContentType, Body: msg.Body})
+	if err != nil {
+		t.Fatalf("FromDelivery: %v", err)
+	}
+	if got.ID() != event.ID() {
+		t.Fatalf("ID = %q, want %q", got.ID(), event.ID())
+	}
+	if got.Source() != event.Source() {
+		t.Fatalf("Source = %q, want %q", got.Source(), event.Source())
+	}
+	if string(got.Data()) != string(event.Data()) {
+		t.Fatalf("Data = %q, want %q", got.Data(), event.Data())
+	}
+}
+
+func TestFromDeliveryRejectsNonCloudEvent(t *testing.T) {
+	_, err := FromDelivery(amqp.Delivery{Body: []byte("plain")})
+	if err != ErrNotCloudEvent {
+		t.Fatalf("FromDelivery() error = %v, want %v", err, ErrNotCloudEvent)
+	}
+}
@@ -0,0 +1,97 @@
+package cloudevents
+
+import (
+	"context"
+
+	amqplib "github.com/rifaniponk/amqp"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	stamqp "github.com/streadway/amqp"
+)
+
+type (
+	eventKey       struct{}
+	traceParentKey struct{}
+	traceStateKey  struct{}
+)
+
+// PublishBefore returns an amqp.PublishingBefore that merges the
+// binary-mode encoding of event into the publishing being built: ContentType
+// and Body are set from event and its CloudEvents headers are copied into
+// p.Headers, leaving everything else constructPublishing already set
+// (Priority, Timestamp, MessageId) and any headers an earlier hook in the
+// same PublishBefore chain injected - notably otel's trace headers - intact.
+// It also propagates traceparent/tracestate via the W3C trace context
+// CloudEvents extension.
+func PublishBefore(event ce.Event) amqplib.PublishingBefore {
+	return func(ctx context.Context, p *stamqp.Publishing) {
+		if tp, ok := ctx.Value(traceParentKey{}).(string); ok {
+			event.SetExtension(headerTraceParent, tp)
+		}
+		if ts, ok := ctx.Value(traceStateKey{}).(string); ok {
+			event.SetExtension(headerTraceState, ts)
+		}
+		msg, err := ToBinaryPublishing(event)
+		if err != nil {
+			return
+		}
+		p.ContentType = msg.ContentType
+		p.Body = msg.Body
+		if p.Headers == nil {
+			p.Headers = stamqp.Table{}
+		}
+		for k, v := range msg.Headers {
+			p.Headers[k] = v
+		}
+	}
+}
+
+// DeliveryBefore returns an amqp.DeliveryBefore that parses d into a
+// CloudEvents Event and stores it on the context, retrievable with
+// EventFromContext. Its traceparent/tracestate extensions, if present, are
+// also placed on the context under the same keys PublishBefore reads them
+// from, so W3C trace context flows end to end.
+func DeliveryBefore() amqplib.DeliveryBefore {
+	return func(ctx context.Context, d *stamqp.Delivery) context.Context {
+		event, err := FromDelivery(*d)
+		if err != nil {
+			return ctx
+		}
+		ctx = context.WithValue(ctx, eventKey{}, event)
+		if tp, ok := event.Extensions()[headerTraceParent].(string); ok {
+			ctx = context.WithValue(ctx, traceParentKey{}, tp)
+		}
+		if ts, ok := event.Extensions()[headerTraceState].(string); ok {
+			ctx = context.WithValue(ctx, traceStateKey{}, ts)
+		}
+		return ctx
+	}
+}
+
+// EventFromContext returns the CloudEvents Event stored by DeliveryBefore,
+// if any.
+func EventFromContext(ctx context.Context) (ce.Event, bool) {
+	event, ok := ctx.Value(eventKey{}).(ce.Event)
+	return event, ok
+}
+
+// Handler processes a CloudEvents Event extracted from a delivery.
+type Handler func(context.Context, ce.Event) error
+
+// SubCloudEvents wraps handler so it can be used wherever amqp.Sub expects a
+// plain delivery handler: it fetches the Event placed on the context by
+// DeliveryBefore and, if one isn't there, parses it from the delivery with
+// FromDelivery, returning that error (e.g. ErrNotCloudEvent) if parsing fails.
+func SubCloudEvents(handler Handler) func(context.Context, stamqp.Delivery) error {
+	return func(ctx context.Context, d stamqp.Delivery) error {
+		event, ok := EventFromContext(ctx)
+		if !ok {
+			var err error
+			event, err = FromDelivery(d)
+			if err != nil {
+				return err
+			}
+		}
+		return handler(ctx, event)
+	}
+}
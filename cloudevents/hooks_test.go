@@ -0,0 +1,115 @@
+package cloudevents
+
+import (
+	"context"
+	"testing"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	stamqp "github.com/streadway/amqp"
+)
+
+func TestPublishBeforeSetsTraceExtensionsFromContext(t *testing.T) {
+	event := ce.NewEvent()
+	event.SetID("event-1")
+	event.SetSource("test/source")
+	event.SetType("test.type")
+	if err := event.SetData("application/json", []byte(`{}`)); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), traceParentKey{}, "00-trace-01")
+	ctx = context.WithValue(ctx, traceStateKey{}, "vendor=state")
+
+	var p stamqp.Publishing
+	PublishBefore(event)(ctx, &p)
+
+	if p.Headers[HeaderPrefix+"traceparent"] != "00-trace-01" {
+		t.Fatalf("traceparent header = %v, want %q", p.Headers[HeaderPrefix+"traceparent"], "00-trace-01")
+	}
+	if p.Headers[HeaderPrefix+"tracestate"] != "vendor=state" {
+		t.Fatalf("tracestate header = %v, want %q", p.Headers[HeaderPrefix+"tracestate"], "vendor=state")
+	}
+}
+
+func TestPublishBeforePreservesFieldsAndHeadersSetByEarlierHooks(t *testing.T) {
+	event := ce.NewEvent()
+	event.SetID("event-1")
+	event.SetSource("test/source")
+	event.SetType("test.type")
+	if err := event.SetData("application/json", []byte(`{}`)); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+
+	p := stamqp.Publishing{
+		Priority:  5,
+		MessageId: "msg-1",
+		Headers:   stamqp.Table{"traceparent": "00-earlier-01"},
+	}
+	PublishBefore(event)(context.Background(), &p)
+
+	if p.Priority != 5 {
+		t.Fatalf("Priority = %d, want 5", p.Priority)
+	}
+	if p.MessageId != "msg-1" {
+		t.Fatalf("MessageId = %q, want %q", p.MessageId, "msg-1")
+	}
+	if p.Headers["traceparent"] != "00-earlier-01" {
+		t.Fatalf("traceparent header = %v, want %q", p.Headers["traceparent"], "00-earlier-01")
+	}
+	if p.Headers[HeaderPrefix+"id"] != "event-1" {
+		t.Fatalf("cloudEvents:id header = %v, want %q", p.Headers[HeaderPrefix+"id"], "event-1")
+	}
+}
+
+func TestDeliveryBeforeStoresEventAndTraceContext(t *testing.T) {
+	d := stamqp.Delivery{
+		ContentType: "application/json",
+		Body:        []byte(`{"k":"v"}`),
+		Headers: stamqp.Table{
+			HeaderPrefix + "id":          "event-1",
+			HeaderPrefix + "source":      "test/source",
+			HeaderPrefix + "type":        "test.type",
+			HeaderPrefix + "specversion": "1.0",
+			HeaderPrefix + "traceparent":  "00-trace-01",
+		},
+	}
+
+	ctx := DeliveryBefore()(context.Background(), &d)
+
+	event, ok := EventFromContext(ctx)
+	if !ok {
+		t.Fatalf("EventFromContext: event not found")
+	}
+	if event.ID() != "event-1" {
+		t.Fatalf("ID = %q, want %q", event.ID(), "event-1")
+	}
+
+	tp, ok := ctx.Value(traceParentKey{}).(string)
+	if !ok || tp != "00-trace-01" {
+		t.Fatalf("traceParentKey value = %v (ok=%v), want %q", tp, ok, "00-trace-01")
+	}
+}
+
+func TestSubCloudEventsUsesEventFromContextWhenPresent(t *testing.T) {
+	event := ce.NewEvent()
+	event.SetID("from-context")
+	event.SetSource("test/source")
+	event.SetType("test.type")
+	if err := event.SetData("application/json", []byte(`{}`)); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	ctx := context.WithValue(context.Background(), eventKey{}, event)
+
+	var got ce.Event
+	handler := SubCloudEvents(func(_ context.Context, e ce.Event) error {
+		got = e
+		return nil
+	})
+
+	if err := handler(ctx, stamqp.Delivery{}); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if got.ID() != "from-context" {
+		t.Fatalf("ID = %q, want %q", got.ID(), "from-context")
+	}
+}
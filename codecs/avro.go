@@ -0,0 +1,78 @@
+package codecs
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+const AvroContentType = "application/vnd.apache.avro+binary"
+
+var errNoAvroSchema = errors.New("codecs: value does not implement AvroSchemer")
+
+// AvroSchemer is implemented by values that carry their own Avro schema,
+// analogous to how amqp.ContentTyper lets a value pick its own content type.
+type AvroSchemer interface {
+	AvroSchema() string
+}
+
+type avroCodec struct{}
+
+func (avroCodec) ContentType() string { return AvroContentType }
+
+// Encode and Decode go through a map[string]interface{} native
+// representation via JSON, since goavro works with Go's native Avro types
+// (maps, slices) rather than arbitrary structs.
+
+func (avroCodec) Encode(v interface{}) ([]byte, error) {
+	s, ok := v.(AvroSchemer)
+	if !ok {
+		return nil, errNoAvroSchema
+	}
+	codec, err := goavro.NewCodec(s.AvroSchema())
+	if err != nil {
+		return nil, err
+	}
+	native, err := toNative(v)
+	if err != nil {
+		return nil, err
+	}
+	return codec.BinaryFromNative(nil, native)
+}
+
+func (avroCodec) Decode(data []byte, v interface{}) error {
+	s, ok := v.(AvroSchemer)
+	if !ok {
+		return errNoAvroSchema
+	}
+	codec, err := goavro.NewCodec(s.AvroSchema())
+	if err != nil {
+		return err
+	}
+	native, _, err := codec.NativeFromBinary(data)
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(native)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, v)
+}
+
+func toNative(v interface{}) (map[string]interface{}, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var native map[string]interface{}
+	if err := json.Unmarshal(buf, &native); err != nil {
+		return nil, err
+	}
+	return native, nil
+}
+
+func init() {
+	Register.Add(avroCodec{})
+}
@@ -0,0 +1,47 @@
+// Package codecs provides the Codec interface and the global registry that
+// amqp.constructPublishing and subscribers fall back on when a client does
+// not supply its own codec via amqp.WithCodec.
+package codecs
+
+import "sync"
+
+// Codec encodes and decodes message bodies for a single content type.
+type Codec interface {
+	// ContentType returns the AMQP content type this codec handles.
+	ContentType() string
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte, v interface{}) error
+}
+
+// Registry is a thread-safe lookup of Codec by content type.
+type Registry struct {
+	mu     sync.RWMutex
+	codecs map[string]Codec
+}
+
+// NewRegistry returns an empty Registry, independent of the global
+// Register. amqp.WithCodec does not take a Registry: it takes a single
+// Codec, which bypasses the global Register for that client.
+func NewRegistry() *Registry {
+	return &Registry{codecs: make(map[string]Codec)}
+}
+
+// Add registers c under c.ContentType(), replacing whatever was registered
+// for that content type before.
+func (r *Registry) Add(c Codec) {
+	r.mu.Lock()
+	r.codecs[c.ContentType()] = c
+	r.mu.Unlock()
+}
+
+// Get returns the codec registered for contentType, if any.
+func (r *Registry) Get(contentType string) (Codec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.codecs[contentType]
+	return c, ok
+}
+
+// Register is the global codec registry. Built-in codecs add themselves to
+// it from their own init functions.
+var Register = NewRegistry()
@@ -0,0 +1,113 @@
+package codecs
+
+import "testing"
+
+type payload struct {
+	Name string `json:"name"`
+	N    int    `json:"n"`
+}
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	c := jsonCodec{}
+	if c.ContentType() != JSONContentType {
+		t.Fatalf("ContentType() = %q, want %q", c.ContentType(), JSONContentType)
+	}
+	in := payload{Name: "a", N: 1}
+	data, err := c.Encode(in)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var out payload
+	if err := c.Decode(data, &out); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if out != in {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", out, in)
+	}
+}
+
+func TestMsgpackCodecRoundTrip(t *testing.T) {
+	c := msgpackCodec{}
+	if c.ContentType() != MsgpackContentType {
+		t.Fatalf("ContentType() = %q, want %q", c.ContentType(), MsgpackContentType)
+	}
+	in := payload{Name: "b", N: 2}
+	data, err := c.Encode(in)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var out payload
+	if err := c.Decode(data, &out); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if out != in {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", out, in)
+	}
+}
+
+type avroPayload struct {
+	Name string `json:"name" avro:"name"`
+}
+
+func (avroPayload) AvroSchema() string {
+	return `{"type":"record","name":"avroPayload","fields":[{"name":"name","type":"string"}]}`
+}
+
+func TestAvroCodecRoundTrip(t *testing.T) {
+	c := avroCodec{}
+	if c.ContentType() != AvroContentType {
+		t.Fatalf("ContentType() = %q, want %q", c.ContentType(), AvroContentType)
+	}
+	in := avroPayload{Name: "c"}
+	data, err := c.Encode(in)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var out avroPayload
+	if err := c.Decode(data, &out); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if out != in {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", out, in)
+	}
+}
+
+func TestAvroCodecRejectsValueWithoutSchema(t *testing.T) {
+	c := avroCodec{}
+	if _, err := c.Encode(payload{}); err != errNoAvroSchema {
+		t.Fatalf("Encode() error = %v, want %v", err, errNoAvroSchema)
+	}
+}
+
+func TestProtobufCodecRejectsNonProtoMessage(t *testing.T) {
+	c := protobufCodec{}
+	if _, err := c.Encode(payload{}); err != errNotProtoMessage {
+		t.Fatalf("Encode() error = %v, want %v", err, errNotProtoMessage)
+	}
+	if err := c.Decode(nil, payload{}); err != errNotProtoMessage {
+		t.Fatalf("Decode() error = %v, want %v", err, errNotProtoMessage)
+	}
+}
+
+func TestRegistryGetAdd(t *testing.T) {
+	r := NewRegistry()
+	if _, ok := r.Get(JSONContentType); ok {
+		t.Fatalf("Get() on empty registry returned ok=true")
+	}
+	r.Add(jsonCodec{})
+	got, ok := r.Get(JSONContentType)
+	if !ok {
+		t.Fatalf("Get() after Add returned ok=false")
+	}
+	if got.ContentType() != JSONContentType {
+		t.Fatalf("Get() returned codec for %q, want %q", got.ContentType(), JSONContentType)
+	}
+}
+
+func TestGlobalRegisterHasBuiltins(t *testing.T) {
+	for _, ct := range []string{JSONContentType, MsgpackContentType, ProtobufContentType, AvroContentType} {
+		if _, ok := Register.Get(ct); !ok {
+			t.Fatalf("global Register missing built-in codec for %q", ct)
+		}
+	}
+}
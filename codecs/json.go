@@ -0,0 +1,21 @@
+package codecs
+
+import "encoding/json"
+
+const JSONContentType = "application/json"
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return JSONContentType }
+
+func (jsonCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Decode(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func init() {
+	Register.Add(jsonCodec{})
+}
@@ -0,0 +1,21 @@
+package codecs
+
+import "github.com/vmihailenco/msgpack/v5"
+
+const MsgpackContentType = "application/msgpack"
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string { return MsgpackContentType }
+
+func (msgpackCodec) Encode(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Decode(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+func init() {
+	Register.Add(msgpackCodec{})
+}
@@ -0,0 +1,35 @@
+package codecs
+
+import (
+	"errors"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const ProtobufContentType = "application/x-protobuf"
+
+var errNotProtoMessage = errors.New("codecs: value does not implement proto.Message")
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string { return ProtobufContentType }
+
+func (protobufCodec) Encode(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, errNotProtoMessage
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Decode(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errNotProtoMessage
+	}
+	return proto.Unmarshal(data, m)
+}
+
+func init() {
+	Register.Add(protobufCodec{})
+}
@@ -0,0 +1,223 @@
+package amqp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+type (
+	// Confirmation reports the broker's ack/nack for a single published
+	// message, correlated back to the message id assigned to it at publish
+	// time.
+	Confirmation struct {
+		amqp.Confirmation
+		MessageId string
+	}
+
+	// Returned is a published message that the broker handed back because
+	// it could not be routed (mandatory) or delivered to a consumer
+	// (immediate).
+	Returned struct {
+		amqp.Return
+		MessageId string
+	}
+)
+
+// ConfirmTracker puts a channel into publisher-confirm mode once and
+// correlates every delivery tag it subsequently hands out with the message
+// id that was assigned to that publishing, so a Confirmation can be matched
+// back to the message that produced it.
+//
+// A single ConfirmTracker is meant to be shared across every PubConfirm
+// call on the same channel: NewConfirmTracker registers the channel's
+// NotifyPublish chan and starts the single watch goroutine that fans
+// confirmations out through Confirmations, and Publish assigns the next
+// sequence number and calls Channel.Publish while holding the same lock for
+// the whole operation, so the tag it records always matches the tag the
+// broker assigns, even when called concurrently from multiple goroutines.
+// Confirmations closes when the channel's NotifyPublish chan closes, which
+// happens on every reconnect; watch itself surfaces whatever was still
+// pending at that point as a synthetic nack on Confirmations before
+// closing it, so callers never need to call Reset themselves - they only
+// need to create a new ConfirmTracker for the next channel incarnation.
+//
+// PubConfirm takes a *ConfirmTracker rather than returning a
+// per-call <-chan Confirmation because a channel's delivery tags are a
+// single sequence shared by every publish on it: correlating confirms
+// correctly requires one watch loop and one pending map per channel, not
+// per call, which is what sharing a tracker across calls gives you.
+type ConfirmTracker struct {
+	ch           *amqp.Channel
+	publish      func(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	notifyReturn func(chan amqp.Return) chan amqp.Return
+
+	mu      sync.Mutex
+	seq     uint64
+	pending map[uint64]string
+
+	confirmations chan Confirmation
+	returnsOnce   sync.Once
+}
+
+// NewConfirmTracker puts ch into publisher-confirm mode and returns a
+// ConfirmTracker ready to publish through.
+func NewConfirmTracker(ctx context.Context, ch *amqp.Channel) (*ConfirmTracker, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, err
+	}
+	t := &ConfirmTracker{
+		ch:            ch,
+		publish:       ch.Publish,
+		notifyReturn:  ch.NotifyReturn,
+		pending:       make(map[uint64]string),
+		confirmations: make(chan Confirmation),
+	}
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 64))
+	go t.watch(ctx, confirms)
+	return t, nil
+}
+
+// Confirmations returns the channel every Confirmation is delivered on,
+// for every Publish call made through this tracker.
+func (t *ConfirmTracker) Confirmations() <-chan Confirmation {
+	return t.confirmations
+}
+
+// Publish assigns msg the next publisher-confirm sequence number and
+// publishes it while holding the tracker's lock for both steps, so the tag
+// recorded for correlation always matches the tag the broker assigns via
+// Channel.Publish, even if two goroutines call Publish on the same tracker
+// concurrently.
+func (t *ConfirmTracker) Publish(exchange, key string, mandatory bool, msg amqp.Publishing) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seq++
+	tag := t.seq
+	t.pending[tag] = msg.MessageId
+	if err := t.publish(exchange, key, mandatory, false, msg); err != nil {
+		delete(t.pending, tag)
+		return err
+	}
+	return nil
+}
+
+// Reset drops every tracked tag, returning their message ids. watch calls
+// this itself once confirms closes, so callers do not need to invoke it
+// directly; it remains exported for tests and for callers that want to
+// drain a tracker without waiting for its channel to close.
+func (t *ConfirmTracker) Reset() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ids := make([]string, 0, len(t.pending))
+	for _, id := range t.pending {
+		ids = append(ids, id)
+	}
+	t.pending = make(map[uint64]string)
+	return ids
+}
+
+// watch is the single goroutine per ConfirmTracker that correlates
+// confirms against tracked tags and emits a Confirmation for each one on
+// confirmations. confirms closes on every reconnect (the channel's
+// NotifyPublish chan closes with the channel), and the broker will never
+// confirm whatever was still pending at that point, so watch drains them
+// as synthetic nacks before it returns and closes confirmations. It
+// returns early, skipping the drain, only if ctx is done first.
+func (t *ConfirmTracker) watch(ctx context.Context, confirms <-chan amqp.Confirmation) {
+	defer close(t.confirmations)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case c, ok := <-confirms:
+			if !ok {
+				t.drain(ctx)
+				return
+			}
+			t.mu.Lock()
+			id := t.pending[c.DeliveryTag]
+			delete(t.pending, c.DeliveryTag)
+			t.mu.Unlock()
+			select {
+			case t.confirmations <- Confirmation{Confirmation: c, MessageId: id}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// drain surfaces every publish Reset finds still pending as a synthetic
+// nack on confirmations, since confirms having closed means the broker
+// will never confirm them. Create a new ConfirmTracker for the next
+// channel incarnation rather than reusing this one.
+func (t *ConfirmTracker) drain(ctx context.Context) {
+	for _, id := range t.Reset() {
+		select {
+		case t.confirmations <- Confirmation{MessageId: id}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchReturns registers the channel's NotifyReturn chan, the first time
+// it is called for this tracker, and invokes onReturn for every message the
+// broker hands back because it could not be routed (mandatory) or
+// delivered (immediate).
+func (t *ConfirmTracker) watchReturns(onReturn func(Returned)) {
+	t.returnsOnce.Do(func() {
+		returns := t.notifyReturn(make(chan amqp.Return, 16))
+		go func() {
+			for r := range returns {
+				onReturn(Returned{Return: r, MessageId: r.MessageId})
+			}
+		}()
+	})
+}
+
+// PubConfirm publishes v to exchange with routing key through tracker,
+// whose Confirmations channel receives the resulting Confirmation once the
+// broker acks or nacks it. ctx is checked before publishing - if it is
+// already done, PubConfirm returns ctx.Err() without calling Publish -
+// since streadway/amqp's Channel.Publish takes no context of its own and
+// so cannot itself be cancelled once started. tracker must have been
+// created with NewConfirmTracker, and should be reused across
+// repeated calls so that Channel.Confirm and NotifyPublish are only ever
+// registered once. If opts includes PublishMandatory, the message is
+// published with the mandatory flag and tracker's NotifyReturn chan is
+// registered (once) so returned messages reach the configured callback.
+func PubConfirm(ctx context.Context, tracker *ConfirmTracker, exchange, key string, v interface{}, opts ...Option) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	msg, err := constructPublishing(v, o.msgOpts.minPriority, o.msgOpts.defaultContentType, o.msgOpts.codec)
+	if err != nil {
+		return err
+	}
+	msg.MessageId = o.msgOpts.idBuilder()
+	if o.publish.mandatory && o.publish.onReturn != nil {
+		tracker.watchReturns(o.publish.onReturn)
+	}
+	return tracker.Publish(exchange, key, o.publish.mandatory, msg)
+}
+
+// PublishMandatory makes PubConfirm set the mandatory flag on its publishing and
+// routes whatever the broker hands back through NotifyReturn to onReturn,
+// instead of letting it disappear silently. It takes onReturn rather than a
+// bare bool because a mandatory publish without anywhere for the broker's
+// Return to go would drop that message the same way an unconfirmed publish
+// would; requiring the callback here is the same "don't silently discard"
+// choice PubConfirm already makes for confirms.
+func PublishMandatory(onReturn func(Returned)) Option {
+	return func(options *options) {
+		options.publish.mandatory = true
+		options.publish.onReturn = onReturn
+	}
+}
@@ -0,0 +1,201 @@
+package amqp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+// TestConfirmTrackerPublishHoldsLockAcrossIO guards against the sequence
+// number assigned to a message desyncing from the order the underlying
+// publish call actually executes. The fake publish function reads back
+// tracker.pending without taking tracker.mu itself, so those reads are only
+// safe while Publish still holds that lock around the publish call; if
+// Publish released the lock first, or never held it around the call, they
+// would race with other goroutines' writes under -race. Tag assignment and
+// the publish call must be one atomic, serialized operation -- which is the
+// bug this test guards against regressing.
+func TestConfirmTrackerPublishHoldsLockAcrossIO(t *testing.T) {
+	tracker := &ConfirmTracker{pending: make(map[uint64]string)}
+
+	var mu sync.Mutex
+	var order []uint64
+	tracker.publish = func(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+		var tag uint64
+		for k, v := range tracker.pending {
+			if v == msg.MessageId {
+				tag = k
+				break
+			}
+		}
+		mu.Lock()
+		order = append(order, tag)
+		mu.Unlock()
+		return nil
+	}
+
+	const n = 100
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_ = tracker.Publish("ex", "key", false, amqp.Publishing{MessageId: fmt.Sprintf("msg-%d", i)})
+		}(i)
+	}
+	wg.Wait()
+
+	if len(order) != n {
+		t.Fatalf("expected %d recorded publishes, got %d", n, len(order))
+	}
+	seen := make(map[uint64]bool, n)
+	for _, tag := range order {
+		if seen[tag] {
+			t.Fatalf("tag %d observed more than once: Publish is not serializing tag assignment with the publish call", tag)
+		}
+		seen[tag] = true
+	}
+}
+
+// TestConfirmTrackerWatchCorrelatesByTag verifies that confirmations are
+// matched back to the message id recorded for their DeliveryTag, even when
+// several "publishers" share the same tracker and its single watch
+// goroutine/channel, which is the whole point of sharing a ConfirmTracker
+// across repeated PubConfirm calls on one channel.
+func TestConfirmTrackerWatchCorrelatesByTag(t *testing.T) {
+	tracker := &ConfirmTracker{
+		pending:       make(map[uint64]string),
+		confirmations: make(chan Confirmation),
+	}
+	tracker.publish = func(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+		return nil
+	}
+
+	if err := tracker.Publish("ex", "key", false, amqp.Publishing{MessageId: "message-a"}); err != nil {
+		t.Fatalf("Publish(message-a): %v", err)
+	}
+	if err := tracker.Publish("ex", "key", false, amqp.Publishing{MessageId: "message-b"}); err != nil {
+		t.Fatalf("Publish(message-b): %v", err)
+	}
+
+	var tagA, tagB uint64
+	for tag, id := range tracker.pending {
+		switch id {
+		case "message-a":
+			tagA = tag
+		case "message-b":
+			tagB = tag
+		}
+	}
+
+	confirms := make(chan amqp.Confirmation, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tracker.watch(ctx, confirms)
+
+	confirms <- amqp.Confirmation{DeliveryTag: tagB, Ack: true}
+	confirms <- amqp.Confirmation{DeliveryTag: tagA, Ack: false}
+
+	got := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		c := <-tracker.confirmations
+		got[c.MessageId] = c.Ack
+	}
+
+	if ack, ok := got["message-a"]; !ok || ack {
+		t.Fatalf("expected message-a to be nacked, got %v (present=%v)", ack, ok)
+	}
+	if ack, ok := got["message-b"]; !ok || !ack {
+		t.Fatalf("expected message-b to be acked, got %v (present=%v)", ack, ok)
+	}
+}
+
+// TestPubConfirmReturnsCtxErrWithoutPublishingWhenCtxDone verifies that
+// PubConfirm checks ctx before publishing, since streadway/amqp's
+// Channel.Publish has no way to cancel a publish already in flight.
+func TestPubConfirmReturnsCtxErrWithoutPublishingWhenCtxDone(t *testing.T) {
+	tracker := &ConfirmTracker{pending: make(map[uint64]string)}
+	published := false
+	tracker.publish = func(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+		published = true
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := PubConfirm(ctx, tracker, "ex", "key", "payload")
+	if err != context.Canceled {
+		t.Fatalf("PubConfirm() error = %v, want %v", err, context.Canceled)
+	}
+	if published {
+		t.Fatalf("PubConfirm published despite a done context")
+	}
+}
+
+// TestConfirmTrackerWatchDrainsPendingAsNacksOnReconnect verifies that once
+// confirms closes (simulating a reconnect, which closes the channel's
+// NotifyPublish chan), watch surfaces every still-pending publish as a
+// synthetic nack on Confirmations instead of silently dropping it.
+func TestConfirmTrackerWatchDrainsPendingAsNacksOnReconnect(t *testing.T) {
+	tracker := &ConfirmTracker{
+		pending:       map[uint64]string{1: "message-a", 2: "message-b"},
+		confirmations: make(chan Confirmation),
+	}
+
+	confirms := make(chan amqp.Confirmation)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tracker.watch(ctx, confirms)
+	close(confirms)
+
+	got := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		c, ok := <-tracker.confirmations
+		if !ok {
+			t.Fatalf("confirmations closed early, got %d of 2 drained nacks", i)
+		}
+		got[c.MessageId] = c.Ack
+	}
+	if ack, ok := got["message-a"]; !ok || ack {
+		t.Fatalf("expected message-a to be drained as a nack, got %v (present=%v)", ack, ok)
+	}
+	if ack, ok := got["message-b"]; !ok || ack {
+		t.Fatalf("expected message-b to be drained as a nack, got %v (present=%v)", ack, ok)
+	}
+
+	if _, ok := <-tracker.confirmations; ok {
+		t.Fatalf("expected confirmations to close after draining")
+	}
+}
+
+// TestConfirmTrackerWatchReturnsInvokesOnce verifies watchReturns only
+// ever registers the channel's NotifyReturn chan once, even if it is
+// called for every PubConfirm call with PublishMandatory set.
+func TestConfirmTrackerWatchReturnsInvokesOnce(t *testing.T) {
+	tracker := &ConfirmTracker{pending: make(map[uint64]string)}
+
+	var registrations int
+	var mu sync.Mutex
+	tracker.notifyReturn = func(ch chan amqp.Return) chan amqp.Return {
+		mu.Lock()
+		registrations++
+		mu.Unlock()
+		close(ch)
+		return ch
+	}
+
+	onReturn := func(Returned) {}
+	tracker.watchReturns(onReturn)
+	tracker.watchReturns(onReturn)
+	tracker.watchReturns(onReturn)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if registrations != 1 {
+		t.Fatalf("expected NotifyReturn to be registered exactly once, got %d", registrations)
+	}
+}
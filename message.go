@@ -24,10 +24,21 @@ type (
 	}
 )
 
+// Codec is re-exported from codecs for convenience, so that implementing a
+// custom codec does not require importing the codecs package directly.
+type Codec = codecs.Codec
+
 var CodecNotFound = errors.New("codec not found")
 
+// ErrDisallowedContentType is returned by DecodeDelivery when a delivery's
+// ContentType is not in the set configured via AllowedContentTypes.
+var ErrDisallowedContentType = errors.New("content type is not allowed")
+
 // constructPublishing uses message options to construct amqp.Publishing.
-func constructPublishing(v interface{}, priority uint8, defaultContentType string) (msg amqp.Publishing, err error) {
+// If codec is nil, the content type is looked up in the global codecs.Register,
+// as before; a non-nil codec (set via WithCodec) is used unconditionally,
+// which lets callers inject a codec without touching global state.
+func constructPublishing(v interface{}, priority uint8, defaultContentType string, codec Codec) (msg amqp.Publishing, err error) {
 	msg.Timestamp = time.Now()
 	msg.Priority = priority
 
@@ -42,10 +53,67 @@ func constructPublishing(v interface{}, priority uint8, defaultContentType strin
 		msg.ContentType = http.DetectContentType(msg.Body)
 	}
 
-	codec, ok := codecs.Register.Get(contentType)
-	if !ok {
-		return msg, CodecNotFound
+	if codec == nil {
+		var ok bool
+		codec, ok = codecs.Register.Get(contentType)
+		if !ok {
+			return msg, CodecNotFound
+		}
 	}
 	msg.Body, err = codec.Encode(v)
 	return
 }
+
+// contentTypeAllowed reports whether contentType passes the subscriber's
+// AllowedContentTypes filter. An empty filter allows everything.
+func contentTypeAllowed(allowed []string, contentType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, t := range allowed {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeDelivery picks a codec for d by its ContentType, falling back to
+// defaultContentType when d.ContentType is empty, and decodes its Body into v.
+// If codec is non-nil (set via WithCodec), it is used unconditionally instead
+// of consulting the global codecs.Register.
+func decodeDelivery(d amqp.Delivery, v interface{}, allowedContentTypes []string, defaultContentType string, codec Codec) error {
+	contentType := d.ContentType
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+	if !contentTypeAllowed(allowedContentTypes, contentType) {
+		return ErrDisallowedContentType
+	}
+	if codec == nil {
+		var ok bool
+		codec, ok = codecs.Register.Get(contentType)
+		if !ok {
+			return CodecNotFound
+		}
+	}
+	return codec.Decode(d.Body, v)
+}
+
+// DecodeDelivery decodes d's Body into v using the codec negotiated from
+// d.ContentType, falling back to SetDefaultContentType's value when
+// d.ContentType is empty, and rejects content types not allowed by
+// AllowedContentTypes with ErrDisallowedContentType. WithCodec replaces the
+// codec lookup with the given codec; the AllowedContentTypes check still
+// applies.
+//
+// It lets callers holding an amqp.Delivery from outside Sub - a manual
+// consumer loop, or another package built on top of this one - apply the
+// same content negotiation Sub uses without duplicating it.
+func DecodeDelivery(d amqp.Delivery, v interface{}, opts ...Option) error {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return decodeDelivery(d, v, o.msgOpts.allowedContentTypes, o.msgOpts.defaultContentType, o.msgOpts.codec)
+}
@@ -0,0 +1,89 @@
+package amqp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+type fakeCodec struct {
+	contentType string
+}
+
+func (c fakeCodec) ContentType() string { return c.contentType }
+
+func (c fakeCodec) Encode(v interface{}) ([]byte, error) {
+	return []byte(v.(string)), nil
+}
+
+func (c fakeCodec) Decode(data []byte, v interface{}) error {
+	p, ok := v.(*string)
+	if !ok {
+		return errors.New("fakeCodec: v is not *string")
+	}
+	*p = string(data)
+	return nil
+}
+
+func TestContentTypeAllowed(t *testing.T) {
+	cases := []struct {
+		name    string
+		allowed []string
+		ct      string
+		want    bool
+	}{
+		{"empty filter allows everything", nil, "application/json", true},
+		{"listed type allowed", []string{"application/json", "application/msgpack"}, "application/msgpack", true},
+		{"unlisted type rejected", []string{"application/json"}, "application/msgpack", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := contentTypeAllowed(c.allowed, c.ct); got != c.want {
+				t.Fatalf("contentTypeAllowed(%v, %q) = %v, want %v", c.allowed, c.ct, got, c.want)
+			}
+		})
+	}
+}
+
+func TestDecodeDeliveryUsesWithCodec(t *testing.T) {
+	d := amqp.Delivery{ContentType: "application/x-fake", Body: []byte("hello")}
+
+	var got string
+	err := DecodeDelivery(d, &got, WithCodec(fakeCodec{contentType: "application/x-fake"}))
+	if err != nil {
+		t.Fatalf("DecodeDelivery: %v", err)
+	}
+	if got != "hello" {
+		t.Fatalf("got %q, want %q", got, "hello")
+	}
+}
+
+func TestDecodeDeliveryRejectsDisallowedContentType(t *testing.T) {
+	d := amqp.Delivery{ContentType: "application/x-fake", Body: []byte("hello")}
+
+	var got string
+	err := DecodeDelivery(d, &got,
+		WithCodec(fakeCodec{contentType: "application/x-fake"}),
+		AllowedContentTypes("application/json"),
+	)
+	if !errors.Is(err, ErrDisallowedContentType) {
+		t.Fatalf("DecodeDelivery() error = %v, want %v", err, ErrDisallowedContentType)
+	}
+}
+
+func TestDecodeDeliveryFallsBackToDefaultContentType(t *testing.T) {
+	d := amqp.Delivery{Body: []byte("hello")}
+
+	var got string
+	err := DecodeDelivery(d, &got,
+		WithCodec(fakeCodec{contentType: "application/x-fake"}),
+		SetDefaultContentType("application/x-fake"),
+	)
+	if err != nil {
+		t.Fatalf("DecodeDelivery: %v", err)
+	}
+	if got != "hello" {
+		t.Fatalf("got %q, want %q", got, "hello")
+	}
+}
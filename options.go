@@ -23,6 +23,10 @@ type (
 			cap  int
 		}
 		subEventChanBuffer int
+		publish            struct {
+			mandatory bool
+			onReturn  func(Returned)
+		}
 		log                struct {
 			debug logger.Logger
 			info  logger.Logger
@@ -36,6 +40,7 @@ type (
 		errorBefore          []ErrorBefore
 		lazyCommands         bool
 		connOpts             []conn.ConnectionOption
+		retry                RetryPolicy
 	}
 
 	MessageIdBuilder func() string                                         // Function, that should return new message Id.
@@ -217,6 +222,64 @@ func SetDefaultContentType(t string) Option {
 	}
 }
 
+// WithCodec sets the codec PubConfirm encodes with and DecodeDelivery
+// decodes with, bypassing the global codecs.Register. Use this when tests or
+// services need to inject a codec without mutating global state.
+func WithCodec(codec Codec) Option {
+	return func(options *options) {
+		options.msgOpts.codec = codec
+	}
+}
+
+// AllowedContentTypes rejects deliveries whose ContentType (or
+// SetDefaultContentType fallback) is not in types, returning
+// ErrDisallowedContentType for them instead of attempting to decode.
+func AllowedContentTypes(types ...string) Option {
+	return func(options *options) {
+		options.msgOpts.allowedContentTypes = types
+	}
+}
+
+// WithRetry sets a retry policy of up to max attempts for a failed
+// delivery, delaying each attempt with an exponentially growing, jittered
+// backoff between base and cap. Sub does not apply the policy by itself;
+// installRetry adds it to the ErrorBefore chain for a channel. See
+// NewRetryErrorBefore for how the retry is carried out.
+func WithRetry(max int, base, cap time.Duration) Option {
+	return func(options *options) {
+		options.retry.Max = max
+		options.retry.Base = base
+		options.retry.Cap = cap
+	}
+}
+
+// WithDelayExchange names the exchange NewRetryErrorBefore declares and
+// publishes retry delay queues through. It has no effect unless WithRetry
+// is also set.
+func WithDelayExchange(exchange string) Option {
+	return func(options *options) {
+		options.retry.DelayExchange = exchange
+	}
+}
+
+// WithDeadLetter sets the exchange and routing key a delivery is published
+// to once it has exhausted its retries.
+func WithDeadLetter(exchange, routingKey string) Option {
+	return func(options *options) {
+		options.retry.DeadLetterExchange = exchange
+		options.retry.DeadLetterKey = routingKey
+	}
+}
+
+// WithPoisonHandler sets a callback invoked with the final delivery and
+// error once a delivery has exhausted its retries, whether or not a
+// dead-letter exchange is configured to publish it to.
+func WithPoisonHandler(h func(amqp.Delivery, error)) Option {
+	return func(options *options) {
+		options.retry.PoisonHandler = h
+	}
+}
+
 var noopMessageIdBuilder = func() string {
 	return ""
 }
@@ -0,0 +1,102 @@
+package otel
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/devimteam/amqp/conn"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ConnectionObserver emits a reconnect counter and a connection-blocked
+// gauge for a single logical connection across its reconnects. Build one
+// with NewConnectionObserver and drive it with WrapDialer, or call Observe
+// directly with every *amqp.Connection a Dialer returns (including the
+// first): the reconnect counter and the gauge callback are registered
+// once, in NewConnectionObserver, and Observe only attaches the new
+// connection's NotifyBlocked watch and increments the counter from the
+// second call on, so it counts reconnects rather than connections.
+//
+// conn.Connect itself cannot call Observe: conn is imported by the root
+// amqp package (for conn.ConnectionOption), and this package imports that
+// root package (for amqplib.PublishingBefore/DeliveryBefore), so conn
+// importing this package back would be an import cycle. WrapDialer is the
+// wiring point instead - pass conn.DialWithDialer a dialer built with it
+// and every connection it returns, including reconnects, is observed
+// automatically.
+type ConnectionObserver struct {
+	reconnects metric.Int64Counter
+
+	mu        sync.Mutex
+	connected bool
+	isBlocked atomic.Int64
+}
+
+// NewConnectionObserver registers the reconnect counter and
+// connection-blocked gauge against mp and returns a ConnectionObserver
+// ready to track a connection's incarnations via Observe.
+func NewConnectionObserver(mp metric.MeterProvider) (*ConnectionObserver, error) {
+	meter := mp.Meter(instrumentationName)
+	reconnects, err := meter.Int64Counter("messaging.rabbitmq.reconnects")
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := meter.Int64ObservableGauge("messaging.rabbitmq.connection_blocked")
+	if err != nil {
+		return nil, err
+	}
+
+	o := &ConnectionObserver{reconnects: reconnects}
+	if _, err := meter.RegisterCallback(func(_ context.Context, obs metric.Observer) error {
+		obs.ObserveInt64(blocked, o.isBlocked.Load())
+		return nil
+	}, blocked); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Observe watches connection's NotifyBlocked notifications until it
+// closes, keeping the connection-blocked gauge up to date, and increments
+// the reconnect counter for every call after the first - the first call
+// establishes the initial connection, which is not itself a reconnect.
+func (o *ConnectionObserver) Observe(connection *amqp.Connection) {
+	o.mu.Lock()
+	reconnect := o.connected
+	o.connected = true
+	o.mu.Unlock()
+	if reconnect {
+		o.reconnects.Add(context.Background(), 1)
+	}
+	o.isBlocked.Store(0)
+
+	blockedCh := connection.NotifyBlocked(make(chan amqp.Blocking))
+	go func() {
+		for b := range blockedCh {
+			if b.Active {
+				o.isBlocked.Store(1)
+			} else {
+				o.isBlocked.Store(0)
+			}
+		}
+	}()
+}
+
+// WrapDialer wraps dialer so that every connection it successfully
+// returns - the initial connection and every reconnect - is passed to
+// o.Observe before the caller sees it. Pass the result to
+// conn.DialWithDialer to get reconnect/connection-blocked metrics from the
+// connection layer without conn needing to import this package; see
+// ConnectionObserver for why that import would cycle.
+func (o *ConnectionObserver) WrapDialer(dialer conn.Dialer) conn.Dialer {
+	return func() (*amqp.Connection, error) {
+		c, err := dialer()
+		if err != nil {
+			return nil, err
+		}
+		o.Observe(c)
+		return c, nil
+	}
+}
@@ -0,0 +1,218 @@
+// Package otel instruments amqp publishing and delivery with OpenTelemetry
+// tracing and metrics, following the OpenTelemetry messaging semantic
+// conventions.
+package otel
+
+import (
+	"context"
+	"time"
+
+	amqplib "github.com/rifaniponk/amqp"
+	"github.com/streadway/amqp"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	systemAttr = "rabbitmq"
+
+	instrumentationName = "github.com/rifaniponk/amqp/otel"
+)
+
+// headerCarrier adapts amqp.Table to propagation.TextMapCarrier so W3C
+// traceparent/baggage headers can be injected into and extracted from AMQP
+// headers.
+type headerCarrier amqp.Table
+
+func (c headerCarrier) Get(key string) string {
+	v, ok := c[key]
+	if !ok {
+		return ""
+	}
+	s, _ := v.(string)
+	return s
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Instruments holds the tracer, meter and instruments shared by WrapPublish
+// and WrapHandler. Build one with NewInstruments and use it to wrap the
+// channel's Publish call and a Sub handler.
+type Instruments struct {
+	tracer    trace.Tracer
+	meter     metric.Meter
+	propag    propagation.TextMapPropagator
+	publishMS metric.Float64Histogram
+	receiveMS metric.Float64Histogram
+	inFlight  metric.Int64UpDownCounter
+	redelivd  metric.Int64Counter
+}
+
+// NewInstruments builds the tracer/meter instruments used by WrapPublish
+// and WrapHandler from tp and mp.
+func NewInstruments(tp trace.TracerProvider, mp metric.MeterProvider) (*Instruments, error) {
+	i := &Instruments{
+		tracer: tp.Tracer(instrumentationName),
+		meter:  mp.Meter(instrumentationName),
+		propag: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+	var err error
+	if i.publishMS, err = i.meter.Float64Histogram("messaging.publish.duration"); err != nil {
+		return nil, err
+	}
+	if i.receiveMS, err = i.meter.Float64Histogram("messaging.receive.duration"); err != nil {
+		return nil, err
+	}
+	if i.inFlight, err = i.meter.Int64UpDownCounter("messaging.process.in_flight"); err != nil {
+		return nil, err
+	}
+	if i.redelivd, err = i.meter.Int64Counter("messaging.redelivered"); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// WrapPublish wraps publish (typically ch.Publish bound to a fixed
+// exchange/routing key) so that calling the result injects the current
+// trace context into msg's headers and measures messaging.publish.duration
+// and the publish span around publish's actual execution, not just the
+// time spent building msg.
+func (i *Instruments) WrapPublish(exchange, routingKey string, publish func(amqp.Publishing) error) func(context.Context, amqp.Publishing) error {
+	return func(ctx context.Context, msg amqp.Publishing) error {
+		start := time.Now()
+		ctx, span := i.tracer.Start(ctx, exchange+" publish", trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", systemAttr),
+				attribute.String("messaging.destination", exchange),
+				attribute.String("messaging.rabbitmq.routing_key", routingKey),
+				attribute.String("messaging.operation", "publish"),
+			))
+		defer span.End()
+
+		if msg.Headers == nil {
+			msg.Headers = amqp.Table{}
+		}
+		i.propag.Inject(ctx, headerCarrier(msg.Headers))
+
+		err := publish(msg)
+		i.publishMS.Record(ctx, time.Since(start).Seconds()*1000)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
+
+// WrapHandler wraps a Sub delivery handler so that calling the result
+// extracts the producer's trace context from the delivery's headers, runs
+// handler inside a process span, and records messaging.receive.duration,
+// in-flight and redelivered metrics around handler's actual execution,
+// instead of deferring to a context cancellation that Sub never triggers.
+func (i *Instruments) WrapHandler(queue string, handler func(context.Context, amqp.Delivery) error) func(context.Context, amqp.Delivery) error {
+	return func(ctx context.Context, d amqp.Delivery) error {
+		start := time.Now()
+		ctx = i.propag.Extract(ctx, headerCarrier(d.Headers))
+		ctx, span := i.tracer.Start(ctx, queue+" process", trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", systemAttr),
+				attribute.String("messaging.destination", queue),
+				attribute.String("messaging.operation", "process"),
+			))
+		defer span.End()
+
+		i.inFlight.Add(ctx, 1)
+		defer i.inFlight.Add(ctx, -1)
+		if d.Redelivered {
+			i.redelivd.Add(ctx, 1)
+		}
+
+		err := handler(ctx, d)
+		i.receiveMS.Record(ctx, time.Since(start).Seconds()*1000)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
+
+// PublishBefore returns an amqp.PublishingBefore that starts a publish span
+// and injects the resulting trace context into the publishing's headers.
+// Because PublishingBefore runs before the publish and cannot observe when
+// it finishes, the span it starts is ended immediately and
+// messaging.publish.duration is not recorded here; use WrapPublish instead
+// when you need that measured around the actual publish call. This exists
+// for client options that only accept a PublishingBefore/DeliveryBefore
+// pair, where WrapPublish's signature does not fit.
+func (i *Instruments) PublishBefore(exchange, routingKey string) amqplib.PublishingBefore {
+	return func(ctx context.Context, msg *amqp.Publishing) {
+		ctx, span := i.tracer.Start(ctx, exchange+" publish", trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", systemAttr),
+				attribute.String("messaging.destination", exchange),
+				attribute.String("messaging.rabbitmq.routing_key", routingKey),
+				attribute.String("messaging.operation", "publish"),
+			))
+		defer span.End()
+
+		if msg.Headers == nil {
+			msg.Headers = amqp.Table{}
+		}
+		i.propag.Inject(ctx, headerCarrier(msg.Headers))
+	}
+}
+
+// DeliveryBefore returns an amqp.DeliveryBefore that extracts the producer's
+// trace context from the delivery's headers, starts a process span linked to
+// it, and records the redelivered counter. As with PublishBefore, a
+// DeliveryBefore hook cannot observe when the handler it precedes finishes,
+// so messaging.receive.duration and messaging.process.in_flight are not
+// recorded here; use WrapHandler instead when you need those measured around
+// the handler's actual execution.
+func (i *Instruments) DeliveryBefore(queue string) amqplib.DeliveryBefore {
+	return func(ctx context.Context, d *amqp.Delivery) context.Context {
+		ctx = i.propag.Extract(ctx, headerCarrier(d.Headers))
+		ctx, span := i.tracer.Start(ctx, queue+" process", trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", systemAttr),
+				attribute.String("messaging.destination", queue),
+				attribute.String("messaging.operation", "process"),
+			))
+		defer span.End()
+		if d.Redelivered {
+			i.redelivd.Add(ctx, 1)
+		}
+		return ctx
+	}
+}
+
+// Instrument builds Instruments from tp and mp and returns the
+// amqp.PublishBefore/amqp.DeliverBefore options that wire them into a
+// client's option list in one step, for clients that take options rather
+// than a direct publish/handler func to wrap. The spans and counters these
+// options produce do not cover the publish/handler duration (see
+// PublishBefore and DeliveryBefore); prefer WrapPublish/WrapHandler directly
+// around the actual publish call and Sub handler when that measurement
+// matters.
+func Instrument(exchange, routingKey, queue string, tp trace.TracerProvider, mp metric.MeterProvider) ([]amqplib.Option, error) {
+	i, err := NewInstruments(tp, mp)
+	if err != nil {
+		return nil, err
+	}
+	return []amqplib.Option{
+		amqplib.PublishBefore(i.PublishBefore(exchange, routingKey)),
+		amqplib.DeliverBefore(i.DeliveryBefore(queue)),
+	}, nil
+}
@@ -0,0 +1,44 @@
+package otel
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+func TestHeaderCarrierGetSet(t *testing.T) {
+	c := headerCarrier(amqp.Table{})
+
+	if got := c.Get("traceparent"); got != "" {
+		t.Fatalf("Get() on empty carrier = %q, want empty", got)
+	}
+
+	c.Set("traceparent", "00-trace-01")
+	if got := c.Get("traceparent"); got != "00-trace-01" {
+		t.Fatalf("Get() = %q, want %q", got, "00-trace-01")
+	}
+}
+
+func TestHeaderCarrierGetIgnoresNonStringValues(t *testing.T) {
+	c := headerCarrier(amqp.Table{"traceparent": 123})
+	if got := c.Get("traceparent"); got != "" {
+		t.Fatalf("Get() on non-string header = %q, want empty", got)
+	}
+}
+
+func TestHeaderCarrierKeys(t *testing.T) {
+	c := headerCarrier(amqp.Table{"traceparent": "a", "tracestate": "b"})
+	keys := c.Keys()
+	sort.Strings(keys)
+	want := []string{"tracestate", "traceparent"}
+	sort.Strings(want)
+	if len(keys) != len(want) {
+		t.Fatalf("Keys() = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("Keys() = %v, want %v", keys, want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package amqp
+
+import (
+	"crypto/rand"
+	"math"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// RetryCountHeader is the header NewRetryErrorBefore uses to track how many
+// times a delivery has been retried.
+const RetryCountHeader = "x-retry-count"
+
+// RetryPolicy configures Sub's retry-with-backoff behaviour. It is
+// populated via WithRetry, WithDeadLetter and WithPoisonHandler rather than
+// constructed directly.
+type RetryPolicy struct {
+	Max                int
+	Base               time.Duration
+	Cap                time.Duration
+	DelayExchange      string
+	DeadLetterExchange string
+	DeadLetterKey      string
+	PoisonHandler      func(amqp.Delivery, error)
+}
+
+func (p RetryPolicy) enabled() bool {
+	return p.Max > 0
+}
+
+// backoff returns a jittered exponential delay for the given retry attempt
+// (1-based), capped at p.Cap. Base*2^(attempt-1) overflows int64 for large
+// attempt/Base combinations, at which point the time.Duration conversion
+// turns negative; that negative value would pass the p.Cap check unclamped
+// and then panic inside rand.Int, so it is clamped to p.Cap (the only sane
+// ceiling available) before the cap comparison rather than after.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := time.Duration(float64(p.Base) * math.Pow(2, float64(attempt-1)))
+	if d <= 0 || (p.Cap > 0 && d > p.Cap) {
+		d = p.Cap
+	}
+	if d <= 0 {
+		return 0
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(d)+1))
+	if err != nil {
+		return d
+	}
+	return time.Duration(n.Int64())
+}
+
+func retryCount(headers amqp.Table) int {
+	v, ok := headers[RetryCountHeader]
+	if !ok {
+		return 0
+	}
+	switch n := v.(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	default:
+		return 0
+	}
+}
+
+// retryQueueKey identifies a per-attempt delay queue. A retry topology can
+// be fed deliveries whose original exchange/routing key differ at the same
+// attempt number - e.g. wildcard topic bindings, or HandlersAmount>1 pulling
+// from several bindings on one channel - and each such delivery needs its
+// own queue, since its x-dead-letter-exchange/routing-key must point back
+// at its own exchange/key rather than whichever one first reached this
+// attempt.
+type retryQueueKey struct {
+	exchange string
+	key      string
+	attempt  int
+}
+
+// retryTopology declares and caches the per-attempt delay queues that back
+// NewRetryErrorBefore. Classic RabbitMQ queues only expire messages once
+// they reach the head of the queue, so a single delay queue fed with
+// per-message Expiration would let a later message with a shorter TTL sit
+// behind an earlier one with a longer TTL. Using one queue per
+// (exchange, key, attempt), with a fixed x-message-ttl for that attempt
+// instead of a per-message Expiration, avoids that head-of-line blocking:
+// every message in a given queue always expires in FIFO order. The ttl is
+// computed once per queue and cached, since RabbitMQ rejects redeclaring a
+// queue with arguments that differ from when it was first declared.
+type retryTopology struct {
+	mu       sync.Mutex
+	declared bool
+	ttl      map[retryQueueKey]time.Duration
+	queue    map[retryQueueKey]string
+}
+
+func (t *retryTopology) queueFor(ch *amqp.Channel, policy RetryPolicy, delayExchange, exchange, key string, attempt int) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ttl == nil {
+		t.ttl = make(map[retryQueueKey]time.Duration)
+		t.queue = make(map[retryQueueKey]string)
+	}
+	k := retryQueueKey{exchange: exchange, key: key, attempt: attempt}
+	if name, ok := t.queue[k]; ok {
+		return name, nil
+	}
+	if !t.declared {
+		if err := ch.ExchangeDeclare(delayExchange, "direct", true, false, false, false, nil); err != nil {
+			return "", err
+		}
+		t.declared = true
+	}
+
+	ttl := policy.backoff(attempt)
+	t.ttl[k] = ttl
+	name := delayExchange + ".retry." + exchange + "." + key + "." + strconv.Itoa(attempt)
+	_, err := ch.QueueDeclare(name, true, false, false, false, amqp.Table{
+		"x-message-ttl":             int64(ttl / time.Millisecond),
+		"x-dead-letter-exchange":    exchange,
+		"x-dead-letter-routing-key": key,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := ch.QueueBind(name, name, delayExchange, false, nil); err != nil {
+		return "", err
+	}
+	t.queue[k] = name
+	return name, nil
+}
+
+// NewRetryErrorBefore returns an ErrorBefore that, on handler error,
+// declares (once, lazily) a per-attempt delay queue bound to delayExchange
+// with a fixed TTL computed from policy's exponential backoff with jitter
+// and d's original exchange/routing key as its x-dead-letter-exchange, then
+// republishes the delivery there with an incremented RetryCountHeader, so
+// it is redelivered to its original destination once the attempt's TTL
+// expires. See retryTopology for why per-attempt queues are used instead of
+// a per-message Expiration. Once policy.Max attempts are exhausted, the
+// delivery is published to policy.DeadLetterExchange/DeadLetterKey instead
+// and policy.PoisonHandler, if set, is invoked. It swallows the original
+// handler error once the delivery has been handed off, so that a single
+// RetryPolicy can be composed with other ErrorBefore hooks without double
+// handling.
+func NewRetryErrorBefore(ch *amqp.Channel, delayExchange string, policy RetryPolicy) ErrorBefore {
+	topo := &retryTopology{}
+	return func(d amqp.Delivery, cause error) error {
+		if !policy.enabled() {
+			return cause
+		}
+		attempt := retryCount(d.Headers) + 1
+		if attempt > policy.Max {
+			return deadLetter(ch, d, cause, policy)
+		}
+
+		queue, err := topo.queueFor(ch, policy, delayExchange, d.Exchange, d.RoutingKey, attempt)
+		if err != nil {
+			return err
+		}
+
+		headers := amqp.Table{}
+		for k, v := range d.Headers {
+			headers[k] = v
+		}
+		headers[RetryCountHeader] = int32(attempt)
+
+		msg := amqp.Publishing{
+			Headers:       headers,
+			ContentType:   d.ContentType,
+			Body:          d.Body,
+			CorrelationId: d.CorrelationId,
+			MessageId:     d.MessageId,
+		}
+		return ch.Publish(delayExchange, queue, false, false, msg)
+	}
+}
+
+// deadLetter hands d off once its retries are exhausted: to
+// policy.DeadLetterExchange/DeadLetterKey if one is configured, and to
+// policy.PoisonHandler either way. When no DeadLetterExchange is
+// configured there is nowhere for d to go, so it returns cause instead of
+// nil - letting Sub nack the delivery - rather than silently dropping it.
+func deadLetter(ch *amqp.Channel, d amqp.Delivery, cause error, policy RetryPolicy) error {
+	if policy.DeadLetterExchange == "" {
+		if policy.PoisonHandler != nil {
+			policy.PoisonHandler(d, cause)
+		}
+		return cause
+	}
+	msg := amqp.Publishing{
+		Headers:       d.Headers,
+		ContentType:   d.ContentType,
+		Body:          d.Body,
+		CorrelationId: d.CorrelationId,
+		MessageId:     d.MessageId,
+	}
+	if err := ch.Publish(policy.DeadLetterExchange, policy.DeadLetterKey, false, false, msg); err != nil {
+		return err
+	}
+	if policy.PoisonHandler != nil {
+		policy.PoisonHandler(d, cause)
+	}
+	return nil
+}
+
+// installRetry appends the ErrorBefore that applies o.retry on ch to the
+// end of o's ErrorBefore chain, after any hooks already in it, using
+// o.retry.DelayExchange (set via WithDelayExchange) to declare the
+// per-attempt delay queues. It is a no-op when WithRetry was never applied.
+// Sub does not call it; it has to be called once per channel after options
+// are applied.
+func (o *options) installRetry(ch *amqp.Channel) {
+	if !o.retry.enabled() {
+		return
+	}
+	o.errorBefore = append(o.errorBefore, NewRetryErrorBefore(ch, o.retry.DelayExchange, o.retry))
+}
@@ -0,0 +1,131 @@
+package amqp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+func TestRetryPolicyBackoffGrowsAndCaps(t *testing.T) {
+	policy := RetryPolicy{Max: 5, Base: 100 * time.Millisecond, Cap: time.Second}
+
+	prevMax := time.Duration(0)
+	for attempt := 1; attempt <= 6; attempt++ {
+		// backoff is jittered, so sample it enough times to pin down the
+		// ceiling for this attempt instead of asserting on one draw.
+		var max time.Duration
+		for i := 0; i < 50; i++ {
+			d := policy.backoff(attempt)
+			if d < 0 {
+				t.Fatalf("attempt %d: backoff returned negative duration %v", attempt, d)
+			}
+			if d > policy.Cap {
+				t.Fatalf("attempt %d: backoff %v exceeds cap %v", attempt, d, policy.Cap)
+			}
+			if d > max {
+				max = d
+			}
+		}
+		if max < prevMax {
+			t.Fatalf("attempt %d: backoff ceiling %v is lower than previous attempt's %v", attempt, max, prevMax)
+		}
+		prevMax = max
+	}
+}
+
+func TestRetryPolicyBackoffZeroCapMeansUncapped(t *testing.T) {
+	policy := RetryPolicy{Max: 3, Base: time.Second}
+	d := policy.backoff(10)
+	if d < 0 {
+		t.Fatalf("backoff returned negative duration %v", d)
+	}
+}
+
+func TestRetryPolicyBackoffClampsOverflowToCap(t *testing.T) {
+	policy := RetryPolicy{Max: 40, Base: time.Second, Cap: time.Minute}
+
+	for attempt := 30; attempt <= 40; attempt++ {
+		// Base*2^(attempt-1) overflows int64 well before attempt 40; backoff
+		// must still return a non-negative duration within the cap instead
+		// of panicking inside rand.Int.
+		d := policy.backoff(attempt)
+		if d < 0 {
+			t.Fatalf("attempt %d: backoff returned negative duration %v", attempt, d)
+		}
+		if d > policy.Cap {
+			t.Fatalf("attempt %d: backoff %v exceeds cap %v", attempt, d, policy.Cap)
+		}
+	}
+}
+
+func TestDeadLetterReturnsCauseWhenNoDeadLetterExchange(t *testing.T) {
+	cause := errors.New("handler failed")
+	err := deadLetter(nil, amqp.Delivery{}, cause, RetryPolicy{})
+	if err != cause {
+		t.Fatalf("deadLetter() = %v, want %v", err, cause)
+	}
+}
+
+func TestDeadLetterInvokesPoisonHandlerWithoutDeadLetterExchange(t *testing.T) {
+	cause := errors.New("handler failed")
+	d := amqp.Delivery{MessageId: "msg-1"}
+	var gotDelivery amqp.Delivery
+	var gotErr error
+	policy := RetryPolicy{PoisonHandler: func(d amqp.Delivery, err error) {
+		gotDelivery = d
+		gotErr = err
+	}}
+
+	if err := deadLetter(nil, d, cause, policy); err != cause {
+		t.Fatalf("deadLetter() = %v, want %v", err, cause)
+	}
+	if gotDelivery.MessageId != "msg-1" {
+		t.Fatalf("PoisonHandler delivery = %+v, want MessageId %q", gotDelivery, "msg-1")
+	}
+	if gotErr != cause {
+		t.Fatalf("PoisonHandler error = %v, want %v", gotErr, cause)
+	}
+}
+
+func TestInstallRetryAppendsErrorBeforeWhenEnabled(t *testing.T) {
+	o := defaultOptions()
+	o.retry = RetryPolicy{Max: 3, Base: time.Millisecond, DelayExchange: "retry.delay"}
+
+	o.installRetry(nil)
+
+	if len(o.errorBefore) != 1 {
+		t.Fatalf("len(errorBefore) = %d, want 1", len(o.errorBefore))
+	}
+}
+
+func TestInstallRetryNoopWhenRetryNotConfigured(t *testing.T) {
+	o := defaultOptions()
+
+	o.installRetry(nil)
+
+	if len(o.errorBefore) != 0 {
+		t.Fatalf("len(errorBefore) = %d, want 0", len(o.errorBefore))
+	}
+}
+
+func TestRetryCountHeaderRoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		headers amqp.Table
+		want    int
+	}{
+		{"missing", amqp.Table{}, 0},
+		{"int32", amqp.Table{RetryCountHeader: int32(2)}, 2},
+		{"int64", amqp.Table{RetryCountHeader: int64(3)}, 3},
+		{"int", amqp.Table{RetryCountHeader: 4}, 4},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := retryCount(c.headers); got != c.want {
+				t.Fatalf("retryCount() = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	amqplib "github.com/rifaniponk/amqp"
+	"github.com/satori/go.uuid"
+	"github.com/streadway/amqp"
+)
+
+// ErrResponseTimeout is returned by Publisher.Endpoint when ctx is done
+// before a matching response arrives.
+var ErrResponseTimeout = errors.New("transport: timed out waiting for response")
+
+// PublisherOption configures a Publisher.
+type PublisherOption func(*Publisher)
+
+// PublisherBefore adds a hook that runs on the request publishing before it
+// is sent, reusing amqp's existing hook type for header/context propagation.
+func PublisherBefore(before ...amqplib.PublishingBefore) PublisherOption {
+	return func(p *Publisher) {
+		p.before = append(p.before, before...)
+	}
+}
+
+// Publisher implements RPC over AMQP: it publishes a request and blocks
+// until a response with a matching CorrelationId arrives on its reply
+// queue, or ctx is done.
+type Publisher struct {
+	ch       *amqp.Channel
+	exchange string
+	key      string
+	encReq   EncodeRequestFunc
+	decResp  DecodeResponseFunc
+	before   []amqplib.PublishingBefore
+
+	replyQueue string
+
+	mu      sync.Mutex
+	pending map[string]chan amqp.Delivery
+}
+
+// NewPublisher declares a private reply queue on ch and returns a Publisher
+// that sends requests to exchange/key and correlates responses delivered to
+// that queue by CorrelationId.
+func NewPublisher(ch *amqp.Channel, exchange, key string, encReq EncodeRequestFunc, decResp DecodeResponseFunc, opts ...PublisherOption) (*Publisher, error) {
+	q, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := ch.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	p := &Publisher{
+		ch:         ch,
+		exchange:   exchange,
+		key:        key,
+		encReq:     encReq,
+		decResp:    decResp,
+		replyQueue: q.Name,
+		pending:    make(map[string]chan amqp.Delivery),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	go p.loop(deliveries)
+	return p, nil
+}
+
+func (p *Publisher) loop(deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		p.mu.Lock()
+		ch, ok := p.pending[d.CorrelationId]
+		delete(p.pending, d.CorrelationId)
+		p.mu.Unlock()
+		if ok {
+			ch <- d
+		}
+	}
+}
+
+// Endpoint returns a go-kit style Endpoint that performs one request/response
+// round trip per call.
+func (p *Publisher) Endpoint() Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		msg := amqp.Publishing{
+			CorrelationId: uuid.NewV4().String(),
+			ReplyTo:       p.replyQueue,
+		}
+		if err := p.encReq(ctx, &msg, request); err != nil {
+			return nil, err
+		}
+		for _, before := range p.before {
+			before(ctx, &msg)
+		}
+
+		wait := make(chan amqp.Delivery, 1)
+		p.mu.Lock()
+		p.pending[msg.CorrelationId] = wait
+		p.mu.Unlock()
+
+		if err := p.ch.Publish(p.exchange, p.key, false, false, msg); err != nil {
+			p.mu.Lock()
+			delete(p.pending, msg.CorrelationId)
+			p.mu.Unlock()
+			return nil, err
+		}
+
+		select {
+		case d := <-wait:
+			return p.decResp(ctx, d)
+		case <-ctx.Done():
+			p.mu.Lock()
+			delete(p.pending, msg.CorrelationId)
+			p.mu.Unlock()
+			return nil, ErrResponseTimeout
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package transport
+
+import (
+	"context"
+
+	amqplib "github.com/rifaniponk/amqp"
+	"github.com/streadway/amqp"
+)
+
+// SubscriberOption configures a Subscriber.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberBefore adds a hook that runs on a delivery before it is
+// decoded, reusing amqp's existing hook type for header/context
+// propagation.
+func SubscriberBefore(before ...amqplib.DeliveryBefore) SubscriberOption {
+	return func(s *Subscriber) {
+		s.before = append(s.before, before...)
+	}
+}
+
+// Subscriber adapts an Endpoint to amqp's delivery handler shape: it
+// decodes the request, invokes endpoint, and publishes the encoded
+// response to Delivery.ReplyTo correlated by CorrelationId.
+type Subscriber struct {
+	ch       *amqp.Channel
+	decReq   DecodeRequestFunc
+	encResp  EncodeResponseFunc
+	endpoint Endpoint
+	before   []amqplib.DeliveryBefore
+}
+
+// NewSubscriber returns a Subscriber that serves endpoint over ch.
+func NewSubscriber(ch *amqp.Channel, decReq DecodeRequestFunc, encResp EncodeResponseFunc, endpoint Endpoint, opts ...SubscriberOption) *Subscriber {
+	s := &Subscriber{
+		ch:       ch,
+		decReq:   decReq,
+		encResp:  encResp,
+		endpoint: endpoint,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Handle decodes d, runs the endpoint, and publishes the response to
+// d.ReplyTo. It does not ack or nack d itself: it returns nil on success
+// and the error of the failing decode, endpoint, encode or publish step
+// otherwise, leaving acknowledgement to the caller, so it can be plugged
+// directly into amqp.Sub's handler.
+func (s *Subscriber) Handle(ctx context.Context, d amqp.Delivery) error {
+	for _, before := range s.before {
+		ctx = before(ctx, &d)
+	}
+
+	req, err := s.decReq(ctx, d)
+	if err != nil {
+		return err
+	}
+	resp, err := s.endpoint(ctx, req)
+	if err != nil {
+		return err
+	}
+	if d.ReplyTo == "" {
+		return nil
+	}
+	msg := amqp.Publishing{CorrelationId: d.CorrelationId}
+	if err := s.encResp(ctx, &msg, resp); err != nil {
+		return err
+	}
+	return s.ch.Publish("", d.ReplyTo, false, false, msg)
+}
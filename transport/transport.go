@@ -0,0 +1,29 @@
+// Package transport implements request/response RPC on top of the
+// fire-and-forget amqp.Pub/amqp.Sub API, in the style of go-kit's
+// transport packages: callers supply Encode/Decode funcs and an Endpoint,
+// and the transport handles the AMQP plumbing.
+package transport
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+)
+
+type (
+	// Endpoint is a go-kit style request handler, decoupled from any
+	// particular transport.
+	Endpoint func(ctx context.Context, request interface{}) (response interface{}, err error)
+
+	// EncodeRequestFunc encodes request into an AMQP publishing.
+	EncodeRequestFunc func(ctx context.Context, msg *amqp.Publishing, request interface{}) error
+
+	// DecodeRequestFunc decodes an AMQP delivery into a request value.
+	DecodeRequestFunc func(ctx context.Context, d amqp.Delivery) (request interface{}, err error)
+
+	// EncodeResponseFunc encodes response into an AMQP publishing.
+	EncodeResponseFunc func(ctx context.Context, msg *amqp.Publishing, response interface{}) error
+
+	// DecodeResponseFunc decodes an AMQP delivery into a response value.
+	DecodeResponseFunc func(ctx context.Context, d amqp.Delivery) (response interface{}, err error)
+)